@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+// ErrorDetail is one entry in an Errors envelope.
+type ErrorDetail struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Errors is the unified error envelope every handler renders as, so clients
+// can rely on a single shape ({"errors": [...]}) whether a request failed
+// for one reason or, as with validation, several at once.
+type Errors struct {
+	HTTPStatusCode int           `json:"-"`
+	List           []ErrorDetail `json:"errors"`
+}
+
+func (e *Errors) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.HTTPStatusCode)
+
+	reqID := middleware.GetReqID(r.Context())
+	for i := range e.List {
+		if e.List[i].RequestID == "" {
+			e.List[i].RequestID = reqID
+		}
+	}
+	return nil
+}
+
+func ErrInvalidRequest(err error) render.Renderer {
+	return &Errors{
+		HTTPStatusCode: http.StatusBadRequest,
+		List:           []ErrorDetail{{Code: http.StatusBadRequest, Message: err.Error()}},
+	}
+}
+
+func ErrRender(err error) render.Renderer {
+	return &Errors{
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		List:           []ErrorDetail{{Code: http.StatusUnprocessableEntity, Message: err.Error()}},
+	}
+}
+
+func ErrInternal(err error) render.Renderer {
+	return &Errors{
+		HTTPStatusCode: http.StatusInternalServerError,
+		List:           []ErrorDetail{{Code: http.StatusInternalServerError, Message: "internal server error"}},
+	}
+}
+
+// ErrValidation builds an Errors envelope with one entry per field failure,
+// for handlers that can detect several invalid fields at once.
+func ErrValidation(fieldErrs map[string]error) render.Renderer {
+	list := make([]ErrorDetail, 0, len(fieldErrs))
+	for field, err := range fieldErrs {
+		list = append(list, ErrorDetail{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+			Field:   field,
+		})
+	}
+	return &Errors{HTTPStatusCode: http.StatusUnprocessableEntity, List: list}
+}
+
+// ErrConflict reports a single conflicting-state error, e.g. a duplicate
+// resource.
+func ErrConflict(err error) render.Renderer {
+	return &Errors{
+		HTTPStatusCode: http.StatusConflict,
+		List:           []ErrorDetail{{Code: http.StatusConflict, Message: err.Error()}},
+	}
+}
+
+func ErrNotFound() render.Renderer {
+	return &Errors{
+		HTTPStatusCode: http.StatusNotFound,
+		List:           []ErrorDetail{{Code: http.StatusNotFound, Message: "resource not found"}},
+	}
+}
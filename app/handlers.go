@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+
+	"github.com/smiler25/experimental-go-statitstics-service/stats"
+)
+
+// GetStats answers GET /stats: it parses the query filter and returns a
+// single aggregated bucket of counts and ok-rate for everything matching
+// it.
+func GetStats(store stats.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+
+		buckets, err := store.Query(filter)
+		if err != nil {
+			log.Printf("[ERROR] query stats: %v request_id=%s", err, middleware.GetReqID(r.Context()))
+			render.Render(w, r, ErrInternal(err))
+			return
+		}
+
+		render.Render(w, r, NewStatsResponse(buckets))
+	}
+}
+
+// GetStatsGrouped answers GET /stats/group: it parses the query filter plus
+// a "group_by" list of dimensions and returns one aggregated bucket per
+// distinct combination of those dimensions.
+func GetStatsGrouped(store stats.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+
+		dims := splitNonEmpty(r.URL.Query().Get("group_by"))
+		if len(dims) == 0 {
+			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("group_by is required")))
+			return
+		}
+		if err := stats.ValidateGroupByDims(dims); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+
+		buckets, err := store.GroupBy(dims, filter)
+		if err != nil {
+			log.Printf("[ERROR] group stats: %v request_id=%s", err, middleware.GetReqID(r.Context()))
+			render.Render(w, r, ErrInternal(err))
+			return
+		}
+
+		render.Render(w, r, NewGroupedStatsResponse(buckets))
+	}
+}
+
+// parseFilter builds a stats.Filter from the query parameters: from/to as
+// RFC3339 timestamps, campaign/template as comma-separated lists, and
+// sliced/empty/recognized/recognized_ok as booleans.
+func parseFilter(q url.Values) (stats.Filter, error) {
+	var filter stats.Filter
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: %w", err)
+		}
+		filter.From = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: %w", err)
+		}
+		filter.To = &t
+	}
+
+	for _, s := range splitNonEmpty(q.Get("campaign")) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid campaign id %q: %w", s, err)
+		}
+		filter.CampaignIDs = append(filter.CampaignIDs, id)
+	}
+
+	filter.Templates = splitNonEmpty(q.Get("template"))
+
+	var err error
+	if filter.Sliced, err = parseBoolParam(q, "sliced"); err != nil {
+		return filter, err
+	}
+	if filter.Empty, err = parseBoolParam(q, "empty"); err != nil {
+		return filter, err
+	}
+	if filter.Recognized, err = parseBoolParam(q, "recognized"); err != nil {
+		return filter, err
+	}
+	if filter.RecognizedOk, err = parseBoolParam(q, "recognized_ok"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseBoolParam(q url.Values, name string) (*bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return &b, nil
+}
+
+func splitNonEmpty(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// StatsResponse is the response payload for GetStats.
+type StatsResponse struct {
+	Buckets []stats.Bucket `json:"buckets"`
+}
+
+func NewStatsResponse(buckets []stats.Bucket) *StatsResponse {
+	return &StatsResponse{Buckets: buckets}
+}
+
+func (rd *StatsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GroupedStatsResponse is the response payload for GetStatsGrouped.
+type GroupedStatsResponse struct {
+	Groups []stats.GroupedBucket `json:"groups"`
+}
+
+func NewGroupedStatsResponse(groups []stats.GroupedBucket) *GroupedStatsResponse {
+	return &GroupedStatsResponse{Groups: groups}
+}
+
+func (rd *GroupedStatsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
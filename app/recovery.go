@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+// panicInfo carries everything an operator needs to reproduce a panic:
+// the panic value itself, the full stack, the matched chi route, the
+// request ID assigned by middleware.RequestID, and the request that
+// triggered it.
+type panicInfo struct {
+	Panic     string      `json:"panic"`
+	Stack     string      `json:"stack"`
+	Route     string      `json:"route"`
+	RequestID string      `json:"request_id"`
+	Method    string      `json:"method"`
+	URL       string      `json:"url"`
+	Headers   http.Header `json:"headers"`
+	Body      string      `json:"body"`
+}
+
+// DevelopmentRecovery builds a recovery middleware. When dev is true, a
+// panic renders the full panicInfo back to the client instead of the
+// sanitized Errors envelope, so handler panics are actionable without
+// shelling into the container to read raw stderr. When dev is false, the
+// same details are logged and the client still gets the sanitized 500.
+func DevelopmentRecovery(dev bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+
+				info := newPanicInfo(r, rvr)
+
+				if dev {
+					renderPanicInfo(w, r, info)
+					return
+				}
+
+				log.Printf("[ERROR] panic: %s route=%s request_id=%s method=%s url=%s headers=%v body=%q\n%s",
+					info.Panic, info.Route, info.RequestID, info.Method, info.URL, info.Headers, info.Body, info.Stack)
+				render.Render(w, r, ErrInternal(fmt.Errorf("%v", rvr)))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newPanicInfo(r *http.Request, rvr interface{}) *panicInfo {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var route string
+	if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil {
+		route = routeCtx.RoutePattern()
+	}
+
+	return &panicInfo{
+		Panic:     fmt.Sprintf("%v", rvr),
+		Stack:     string(debug.Stack()),
+		Route:     route,
+		RequestID: middleware.GetReqID(r.Context()),
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		Headers:   r.Header,
+		Body:      string(body),
+	}
+}
+
+func renderPanicInfo(w http.ResponseWriter, r *http.Request, info *panicInfo) {
+	w.WriteHeader(http.StatusInternalServerError)
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(info)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = panicPageTemplate.Execute(w, info)
+}
+
+var panicPageTemplate = template.Must(template.New("panic").Parse(`<!DOCTYPE html>
+<html>
+<head><title>panic: {{.Panic}}</title></head>
+<body>
+<h1>panic: {{.Panic}}</h1>
+<p><b>route:</b> {{.Route}}</p>
+<p><b>request_id:</b> {{.RequestID}}</p>
+<p><b>{{.Method}}</b> {{.URL}}</p>
+<h2>Headers</h2>
+<pre>{{range $k, $v := .Headers}}{{$k}}: {{$v}}
+{{end}}</pre>
+<h2>Body</h2>
+<pre>{{.Body}}</pre>
+<h2>Stack</h2>
+<pre>{{.Stack}}</pre>
+</body>
+</html>
+`))
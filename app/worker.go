@@ -1,119 +1,53 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"github.com/streadway/amqp"
 	"log"
-	"os"
 	"strconv"
-	"sync"
-)
-
-type Message struct {
-	CampaignId string `json:"campaign_id"`
-	QId        uint   `json:"questionary_id"`
-}
+	"time"
 
-var (
-	rabbitHost string
-	rabbitAddr string
-	rabbitConn *amqp.Connection
-	rabbitChan *amqp.Channel
-	queueName  string
-	numWorkers = 1
+	"github.com/smiler25/experimental-go-statitstics-service/config"
+	"github.com/smiler25/experimental-go-statitstics-service/consumer"
+	"github.com/smiler25/experimental-go-statitstics-service/stats"
 )
 
-func init() {
-	rabbitHost = os.Getenv("RABBIT_HOST")
-	rPort := os.Getenv("RABBIT_PORT")
-	rUser := os.Getenv("RABBIT_USER")
-	rPassword := os.Getenv("RABBIT_PASSWORD")
-	queueName = os.Getenv("RABBIT_QUEUE")
-	if rabbitHost == "" || rUser == "" || rPassword == "" || queueName == "" {
-		log.Fatal("[ERROR] RABBIT_HOST, RABBIT_USER, RABBIT_PASSWORD, RABBIT_QUEUE environment not specified")
-	}
-	if rPort == "" {
-		rPort = "5672"
-	}
-	rabbitAddr = fmt.Sprintf("amqp://%s:%s@%s:%s/", rUser, rPassword, rabbitHost, rPort)
-
-	nw := os.Getenv("WORKERS")
-	if nw != "" {
-		numWorkers, _ = strconv.Atoi(nw)
-	}
+// newConsumerPool builds the ConsumerPool that drives the RabbitMQ queue
+// from a resolved Config, wiring statHandler in as the business logic
+// behind the AMQP plumbing, recording into store.
+func newConsumerPool(cfg *config.Config, store stats.Store) *consumer.ConsumerPool {
+	return consumer.NewConsumerPool(consumer.Config{
+		Addr:               cfg.RabbitAddr(),
+		Queue:              cfg.Rabbit.Queue,
+		Workers:            cfg.Workers,
+		MaxRetries:         cfg.Rabbit.MaxRetries,
+		DeadLetterExchange: cfg.Rabbit.DeadLetterExchange,
+		MinBackoff:         time.Duration(cfg.Rabbit.MinBackoffSeconds) * time.Second,
+		MaxBackoff:         time.Duration(cfg.Rabbit.MaxBackoffSeconds) * time.Second,
+	}, statHandler{store: store})
 }
 
-func Consume() {
-	var err error
-
-	rabbitConn, err = amqp.Dial(rabbitAddr)
-
-	if err != nil {
-		log.Panic("[ERROR] Dial error " + err.Error())
-	}
-
-	rabbitChan, err = rabbitConn.Channel()
-	if err != nil {
-		log.Panic("[ERROR] open channel error " + err.Error())
-	}
-	defer rabbitChan.Close()
-
-	q, err := rabbitChan.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
-	)
-	if err != nil {
-		log.Panic("[ERROR] QueueDeclare error " + err.Error())
-	}
+// statHandler turns a consumer.Message into a stats.Stat and records it to
+// a stats.Store, keeping the ConsumerPool's AMQP plumbing ignorant of what
+// the messages mean.
+type statHandler struct {
+	store stats.Store
+}
 
-	err = rabbitChan.Qos(
-		1,     // prefetch count
-		0,     // prefetch size
-		false, // global
-	)
+func (h statHandler) Handle(ctx context.Context, msg consumer.Message) error {
+	campaign, err := strconv.ParseInt(msg.CampaignId, 10, 64)
 	if err != nil {
-		log.Panic("[ERROR] set Qos error " + err.Error())
+		return fmt.Errorf("invalid campaign id %q: %w", msg.CampaignId, err)
 	}
 
-	tasks, err := rabbitChan.Consume(
-		q.Name, // queue
-		"",     // consumer
-		false,  // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
-	)
-	if err != nil {
-		log.Panic("[ERROR] register consumer error " + err.Error())
+	stat := stats.Stat{
+		Datetime: time.Now(),
+		Campaign: campaign,
 	}
-
-	log.Printf("[INFO] Running consumer host=%s queue=%s workers=%d", rabbitHost, queueName, numWorkers)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	for i := 0; i <= numWorkers; i++ {
-		go worker(tasks)
+	if err := h.store.Record(stat); err != nil {
+		return fmt.Errorf("record stat: %w", err)
 	}
-	wg.Wait()
-}
-
-func worker(tasks <-chan amqp.Delivery) {
-	for taskItem := range tasks {
-		task := &Message{}
-		err := json.Unmarshal(taskItem.Body, task)
-		if err != nil {
-			fmt.Println("[ERROR] cant unpack json", err)
-			taskItem.Ack(false)
-			continue
-		}
-		fmt.Printf("[DEBUG] incoming task %+v\n", task)
 
-		taskItem.Ack(false)
-	}
+	log.Printf("[DEBUG] recorded stat campaign=%d questionary_id=%d", campaign, msg.QId)
+	return nil
 }
@@ -0,0 +1,125 @@
+// Package config loads the service configuration from a stack of providers,
+// merging command-line flags, environment variables, a YAML file and
+// compiled-in defaults into a single typed struct.
+package config
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Config is the fully resolved configuration for the service.
+type Config struct {
+	HTTP struct {
+		Addr string `flag:"http.addr" env:"HTTP_ADDR" yaml:"addr" default:":3333"`
+	} `yaml:"http"`
+
+	Rabbit struct {
+		Host     string `flag:"rabbit.host" env:"RABBIT_HOST" yaml:"host"`
+		Port     string `flag:"rabbit.port" env:"RABBIT_PORT" yaml:"port" default:"5672"`
+		User     string `flag:"rabbit.user" env:"RABBIT_USER" yaml:"user"`
+		Password string `flag:"rabbit.password" env:"RABBIT_PASSWORD" yaml:"password"`
+		Queue    string `flag:"rabbit.queue" env:"RABBIT_QUEUE" yaml:"queue"`
+
+		MaxRetries         int    `flag:"rabbit.max_retries" env:"RABBIT_MAX_RETRIES" yaml:"max_retries" default:"5"`
+		DeadLetterExchange string `flag:"rabbit.dlx" env:"RABBIT_DLX" yaml:"dlx" default:"stats.dlx"`
+		MinBackoffSeconds  int    `flag:"rabbit.min_backoff_seconds" env:"RABBIT_MIN_BACKOFF_SECONDS" yaml:"min_backoff_seconds" default:"1"`
+		MaxBackoffSeconds  int    `flag:"rabbit.max_backoff_seconds" env:"RABBIT_MAX_BACKOFF_SECONDS" yaml:"max_backoff_seconds" default:"30"`
+	} `yaml:"rabbit"`
+
+	Workers  int    `flag:"workers" env:"WORKERS" yaml:"workers" default:"1"`
+	LogLevel string `flag:"log.level" env:"LOG_LEVEL" yaml:"log.level" default:"INFO"`
+
+	Shutdown struct {
+		DrainTimeoutSeconds int `flag:"shutdown.drain_timeout_seconds" env:"SHUTDOWN_DRAIN_TIMEOUT_SECONDS" yaml:"drain_timeout_seconds" default:"15"`
+	} `yaml:"shutdown"`
+
+	Postgres struct {
+		// DSN is a libpq connection string. Left empty, the service falls
+		// back to an in-memory stats store, which is fine for local
+		// development but loses data on restart.
+		DSN string `flag:"postgres.dsn" env:"POSTGRES_DSN" yaml:"dsn"`
+	} `yaml:"postgres"`
+}
+
+// RabbitAddr builds the amqp:// connection string from the Rabbit fields.
+func (c *Config) RabbitAddr() string {
+	return fmt.Sprintf("amqp://%s:%s@%s:%s/", c.Rabbit.User, c.Rabbit.Password, c.Rabbit.Host, c.Rabbit.Port)
+}
+
+// Provider supplies a raw string value for a dotted field path (e.g.
+// "rabbit.host"). ok is false when the provider has no opinion about path,
+// so the Loader can fall through to the next provider in the stack.
+type Provider interface {
+	Value(path string) (value string, ok bool)
+}
+
+// Loader merges a stack of Providers into a typed Config. Providers are
+// consulted in order, highest precedence first, and the first one to return
+// ok=true wins for a given field.
+type Loader struct {
+	providers []Provider
+	paths     []string
+	cliFlags  *flag.FlagSet
+}
+
+// defaultFilePaths are the locations searched for a configuration file, in
+// order, by the provider stack NewLoader builds.
+var defaultFilePaths = []string{
+	"./config.yaml",
+	"./config.yml",
+	"/etc/statistics-service/config.yaml",
+}
+
+// NewLoader builds the default provider stack, in precedence order:
+// command-line flags, environment variables, the first config file found on
+// Paths(), then the compiled defaults declared via the "default" struct tag.
+func NewLoader() *Loader {
+	cli := NewCommandLineProvider()
+	return &Loader{
+		providers: []Provider{
+			cli,
+			NewEnvProvider(),
+			NewFileProvider(defaultFilePaths...),
+			newDefaultsProvider(),
+		},
+		paths:    defaultFilePaths,
+		cliFlags: cli.(*cliProvider).fs,
+	}
+}
+
+// Paths returns the directories/files searched for a configuration file, in
+// search order.
+func (l *Loader) Paths() []string {
+	return l.paths
+}
+
+// Flags returns the FlagSet this Loader's command-line provider parses
+// Config fields from. Callers that need a few flags of their own alongside
+// the generated ones (e.g. main's "-dev") should register them here, before
+// calling Load, rather than on a separate FlagSet: flag.FlagSet.Parse stops
+// at the first flag it doesn't recognize, so any app flag registered
+// elsewhere on the command line would silently cut off every Config flag
+// that follows it.
+func (l *Loader) Flags() *flag.FlagSet {
+	return l.cliFlags
+}
+
+// Load resolves every tagged field on cfg, which must be a pointer to a
+// Config-shaped struct, walking the provider stack in precedence order for
+// each field and stopping at the first provider that has a value.
+func (l *Loader) Load(cfg *Config) error {
+	for _, f := range fields(cfg) {
+		for _, p := range l.providers {
+			raw, ok := p.Value(f.path)
+			if !ok {
+				continue
+			}
+			if err := f.set(raw); err != nil {
+				return fmt.Errorf("config: %s: %w", f.path, err)
+			}
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewCommandLineProviderDoesNotPanicWhenCalledTwice(t *testing.T) {
+	NewCommandLineProvider()
+	NewCommandLineProvider()
+}
+
+func TestLoaderPrecedenceCLIOverEnvOverDefault(t *testing.T) {
+	t.Setenv("WORKERS", "5")
+
+	origArgs := os.Args
+	os.Args = []string{origArgs[0], "-workers", "9"}
+	defer func() { os.Args = origArgs }()
+
+	var cfg Config
+	if err := NewLoader().Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Workers != 9 {
+		t.Fatalf("Workers = %d, want 9 (the -workers flag should win over WORKERS env)", cfg.Workers)
+	}
+}
+
+func TestLoaderPrecedenceEnvOverDefault(t *testing.T) {
+	t.Setenv("WORKERS", "5")
+
+	origArgs := os.Args
+	os.Args = []string{origArgs[0]}
+	defer func() { os.Args = origArgs }()
+
+	var cfg Config
+	if err := NewLoader().Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Workers != 5 {
+		t.Fatalf("Workers = %d, want 5 (the WORKERS env var should win over the compiled default)", cfg.Workers)
+	}
+}
+
+func TestLoaderSurvivesFlagsRegisteredByTheCaller(t *testing.T) {
+	// Mirrors app/app.go: the "-dev" flag isn't a Config field, so it's only
+	// parseable if the caller registers it on the same FlagSet Load() parses
+	// -workers from. flag.FlagSet.Parse stops at the first flag it doesn't
+	// recognize, so registering "-dev" on a separate set would silently
+	// drop "-workers" here too.
+	origArgs := os.Args
+	os.Args = []string{origArgs[0], "-dev", "-workers", "9"}
+	defer func() { os.Args = origArgs }()
+
+	loader := NewLoader()
+	loader.Flags().Bool("dev", false, "")
+
+	var cfg Config
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Workers != 9 {
+		t.Fatalf("Workers = %d, want 9 (a caller-registered flag ahead of it should not cut off parsing)", cfg.Workers)
+	}
+}
+
+func TestLoaderFallsBackToDefault(t *testing.T) {
+	origArgs := os.Args
+	os.Args = []string{origArgs[0]}
+	defer func() { os.Args = origArgs }()
+
+	var cfg Config
+	if err := NewLoader().Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Workers != 1 {
+		t.Fatalf("Workers = %d, want 1 (the compiled default)", cfg.Workers)
+	}
+	if cfg.HTTP.Addr != ":3333" {
+		t.Fatalf("HTTP.Addr = %q, want %q", cfg.HTTP.Addr, ":3333")
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// field describes one leaf, settable field of a Config struct together with
+// the tags providers use to look it up.
+type field struct {
+	path  string // from the "flag" tag, e.g. "rabbit.host"
+	env   string // from the "env" tag
+	dflt  string // from the "default" tag
+	value reflect.Value
+}
+
+func (f field) set(raw string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("want int, got %q", raw)
+		}
+		f.value.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("want bool, got %q", raw)
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field kind %s", f.value.Kind())
+	}
+	return nil
+}
+
+// fields walks cfg's struct tree and returns every leaf field tagged with
+// "flag", recursing into every nested struct field whatever its tags.
+func fields(cfg *Config) []field {
+	return collectFields(reflect.ValueOf(cfg).Elem())
+}
+
+func collectFields(v reflect.Value) []field {
+	var out []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, collectFields(fv)...)
+			continue
+		}
+
+		path := sf.Tag.Get("flag")
+		if path == "" {
+			continue
+		}
+		out = append(out, field{
+			path:  path,
+			env:   sf.Tag.Get("env"),
+			dflt:  sf.Tag.Get("default"),
+			value: fv,
+		})
+	}
+	return out
+}
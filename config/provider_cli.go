@@ -0,0 +1,49 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+)
+
+// cliProvider resolves field values from command-line flags registered
+// automatically from each field's "flag" struct tag, e.g. "rabbit.host"
+// becomes -rabbit.host.
+type cliProvider struct {
+	fs     *flag.FlagSet
+	values map[string]*string
+}
+
+// NewCommandLineProvider registers one string flag per tagged Config field
+// on a private flag.FlagSet and returns a Provider backed by the parsed
+// values. A private set (rather than flag.CommandLine) means constructing a
+// second Loader in the same process, as a table test does per case, doesn't
+// panic with "flag redefined".
+func NewCommandLineProvider() Provider {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	p := &cliProvider{fs: fs, values: map[string]*string{}}
+	for _, f := range fields(&Config{}) {
+		if _, registered := p.values[f.path]; registered {
+			continue
+		}
+		p.values[f.path] = fs.String(f.path, "", "override "+f.path)
+	}
+	return p
+}
+
+func (p *cliProvider) Value(path string) (string, bool) {
+	if !p.fs.Parsed() {
+		// Errors (unknown flags, -h, ...) are swallowed: flags parsed before
+		// the error keep their values, and for every other field the CLI
+		// provider has no opinion and the next provider in the stack takes
+		// over, same as a missing env var or config file.
+		_ = p.fs.Parse(os.Args[1:])
+	}
+	v, ok := p.values[path]
+	if !ok || *v == "" {
+		return "", false
+	}
+	return *v, true
+}
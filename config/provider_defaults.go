@@ -0,0 +1,23 @@
+package config
+
+// defaultsProvider resolves field values from the "default" struct tag
+// compiled into Config. It is always last in the provider stack and never
+// reports a miss for a field that declares a default tag.
+type defaultsProvider struct {
+	defaultByPath map[string]string
+}
+
+func newDefaultsProvider() Provider {
+	p := &defaultsProvider{defaultByPath: map[string]string{}}
+	for _, f := range fields(&Config{}) {
+		if f.dflt != "" {
+			p.defaultByPath[f.path] = f.dflt
+		}
+	}
+	return p
+}
+
+func (p *defaultsProvider) Value(path string) (string, bool) {
+	v, ok := p.defaultByPath[path]
+	return v, ok
+}
@@ -0,0 +1,30 @@
+package config
+
+import "os"
+
+// envProvider resolves field values from environment variables, keyed by
+// each field's "env" struct tag.
+type envProvider struct {
+	envByPath map[string]string
+}
+
+// NewEnvProvider builds a Provider backed by os.LookupEnv, using the "env"
+// tag of each Config field to pick the variable name. A variable that is set
+// but empty still counts as a value.
+func NewEnvProvider() Provider {
+	p := &envProvider{envByPath: map[string]string{}}
+	for _, f := range fields(&Config{}) {
+		if f.env != "" {
+			p.envByPath[f.path] = f.env
+		}
+	}
+	return p
+}
+
+func (p *envProvider) Value(path string) (string, bool) {
+	name, ok := p.envByPath[path]
+	if !ok {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
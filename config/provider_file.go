@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// fileProvider resolves field values from the first readable YAML (or JSON,
+// which parses as a YAML subset) file found among its search paths.
+type fileProvider struct {
+	values map[string]string
+}
+
+// NewFileProvider builds a Provider that reads the first file found among
+// paths, flattening its nested keys ("rabbit.host") to match the "flag"/
+// "yaml" tags on Config. A missing file is not an error: the provider simply
+// has no opinion on any field.
+func NewFileProvider(paths ...string) Provider {
+	p := &fileProvider{values: map[string]string{}}
+
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			continue
+		}
+		flatten("", doc, p.values)
+		break
+	}
+
+	return p
+}
+
+func flatten(prefix string, doc map[string]interface{}, out map[string]string) {
+	for key, val := range doc {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		switch v := val.(type) {
+		case map[string]interface{}:
+			flatten(path, v, out)
+		case map[interface{}]interface{}:
+			nested := make(map[string]interface{}, len(v))
+			for k, vv := range v {
+				nested[fmt.Sprintf("%v", k)] = vv
+			}
+			flatten(path, nested, out)
+		default:
+			out[path] = fmt.Sprintf("%v", v)
+		}
+	}
+}
+
+func (p *fileProvider) Value(path string) (string, bool) {
+	v, ok := p.values[path]
+	return v, ok
+}
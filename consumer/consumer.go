@@ -0,0 +1,318 @@
+// Package consumer implements a worker-pool based RabbitMQ consumer: a
+// bounded pool of workers pulls deliveries off a queue, retries failures a
+// configurable number of times, and finally routes exhausted messages to a
+// dead-letter exchange. Business logic lives behind the Handler interface so
+// it stays independent of the AMQP plumbing.
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Message is the payload carried on the queue.
+type Message struct {
+	CampaignId string `json:"campaign_id"`
+	QId        uint   `json:"questionary_id"`
+}
+
+// Handler processes one decoded Message. A non-nil error causes the
+// delivery to be retried (and eventually dead-lettered) rather than acked.
+type Handler interface {
+	Handle(ctx context.Context, msg Message) error
+}
+
+// Config configures a ConsumerPool.
+type Config struct {
+	Addr  string
+	Queue string
+
+	// Workers is both the number of goroutines pulling deliveries and the
+	// channel prefetch count.
+	Workers int
+
+	// MaxRetries is how many times a failed delivery is requeued before it
+	// is routed to DeadLetterExchange.
+	MaxRetries int
+
+	// DeadLetterExchange, if set, is declared and bound to a
+	// Queue+".dlq" queue, and referenced as the main queue's
+	// x-dead-letter-exchange.
+	DeadLetterExchange string
+
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// reconnect attempts after the connection or channel is lost.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+const consumerTag = "stats-consumer-pool"
+
+// ConsumerPool runs a fixed-size pool of workers against a RabbitMQ queue,
+// reconnecting with exponential backoff when the connection drops, until
+// its Run context is cancelled.
+type ConsumerPool struct {
+	cfg     Config
+	handler Handler
+
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+// NewConsumerPool builds a ConsumerPool, filling in sane defaults for any
+// zero-valued Workers/MaxRetries/backoff fields.
+func NewConsumerPool(cfg Config, handler Handler) *ConsumerPool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	return &ConsumerPool{cfg: cfg, handler: handler}
+}
+
+// Run dials the broker, declares the queue topology and consumes deliveries
+// until ctx is cancelled, reconnecting with exponential backoff whenever the
+// connection or channel closes unexpectedly — whether that happens at dial
+// time or mid-session (the amqp.ErrClosed case). Backoff only resets to
+// MinBackoff once a session has stayed up at least MaxBackoff, so a broker
+// that accepts a connection and immediately closes it doesn't reset backoff
+// on every attempt and spin in a tight reconnect loop. It returns nil once
+// ctx is cancelled and in-flight deliveries have drained.
+func (p *ConsumerPool) Run(ctx context.Context) error {
+	backoff := p.cfg.MinBackoff
+
+	for ctx.Err() == nil {
+		if err := p.dial(); err != nil {
+			log.Printf("[ERROR] consumer: dial %s: %v, retrying in %s", p.cfg.Addr, err, backoff)
+			if !sleep(ctx, backoff) {
+				return nil
+			}
+			backoff = nextBackoff(backoff, p.cfg.MaxBackoff)
+			continue
+		}
+
+		sessionStart := time.Now()
+		err := p.consume(ctx)
+		sessionDuration := time.Since(sessionStart)
+		p.closeConn()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+
+		log.Printf("[ERROR] consumer: lost connection: %v, reconnecting in %s", err, backoff)
+		if !sleep(ctx, backoff) {
+			return nil
+		}
+
+		if sessionDuration >= p.cfg.MaxBackoff {
+			backoff = p.cfg.MinBackoff
+		} else {
+			backoff = nextBackoff(backoff, p.cfg.MaxBackoff)
+		}
+	}
+
+	return nil
+}
+
+func (p *ConsumerPool) dial() error {
+	conn, err := amqp.Dial(p.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("open channel: %w", err)
+	}
+
+	if err := ch.Qos(p.cfg.Workers, 0, false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("set qos: %w", err)
+	}
+
+	if err := p.declareTopology(ch); err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+
+	p.conn = conn
+	p.ch = ch
+	return nil
+}
+
+func (p *ConsumerPool) declareTopology(ch *amqp.Channel) error {
+	args := amqp.Table{}
+
+	if p.cfg.DeadLetterExchange != "" {
+		if err := ch.ExchangeDeclare(p.cfg.DeadLetterExchange, "fanout", true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare dead-letter exchange: %w", err)
+		}
+
+		dlq, err := ch.QueueDeclare(p.cfg.Queue+".dlq", true, false, false, false, nil)
+		if err != nil {
+			return fmt.Errorf("declare dead-letter queue: %w", err)
+		}
+
+		if err := ch.QueueBind(dlq.Name, "", p.cfg.DeadLetterExchange, false, nil); err != nil {
+			return fmt.Errorf("bind dead-letter queue: %w", err)
+		}
+
+		args["x-dead-letter-exchange"] = p.cfg.DeadLetterExchange
+	}
+
+	_, err := ch.QueueDeclare(p.cfg.Queue, true, false, false, false, args)
+	if err != nil {
+		return fmt.Errorf("declare queue: %w", err)
+	}
+	return nil
+}
+
+func (p *ConsumerPool) closeConn() {
+	if p.ch != nil {
+		p.ch.Close()
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// consume registers the consumer tag, fans deliveries out to p.cfg.Workers
+// goroutines, and blocks until ctx is cancelled or the channel closes. On
+// ctx cancellation it cancels the consumer so the broker stops pushing new
+// deliveries, then waits for the workers to drain whatever is already
+// in-flight before closing the channel.
+func (p *ConsumerPool) consume(ctx context.Context) error {
+	deliveries, err := p.ch.Consume(p.cfg.Queue, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("register consumer: %w", err)
+	}
+
+	closed := p.ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.worker(ctx, deliveries)
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = p.ch.Cancel(consumerTag, false)
+		wg.Wait()
+		return nil
+	case amqpErr := <-closed:
+		wg.Wait()
+		if amqpErr != nil {
+			return amqpErr
+		}
+		return amqp.ErrClosed
+	}
+}
+
+func (p *ConsumerPool) worker(ctx context.Context, deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		p.handle(ctx, d)
+	}
+}
+
+func (p *ConsumerPool) handle(ctx context.Context, d amqp.Delivery) {
+	var msg Message
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		log.Printf("[ERROR] consumer: unmarshal delivery: %v", err)
+		p.retryOrDeadLetter(d)
+		return
+	}
+
+	if err := p.handler.Handle(ctx, msg); err != nil {
+		log.Printf("[ERROR] consumer: handle %+v: %v", msg, err)
+		p.retryOrDeadLetter(d)
+		return
+	}
+
+	_ = d.Ack(false)
+}
+
+// retryOrDeadLetter requeues d behind an incremented x-retry header, or
+// Nacks it without requeue once MaxRetries is exhausted so it lands on the
+// dead-letter exchange declared alongside the main queue.
+func (p *ConsumerPool) retryOrDeadLetter(d amqp.Delivery) {
+	retries := retryCount(d) + 1
+	if retries > p.cfg.MaxRetries {
+		log.Printf("[ERROR] consumer: giving up after %d retries, dead-lettering", retries-1)
+		_ = d.Nack(false, false)
+		return
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers["x-retry"] = int32(retries)
+
+	err := p.ch.Publish("", d.RoutingKey, false, false, amqp.Publishing{
+		ContentType: d.ContentType,
+		Body:        d.Body,
+		Headers:     headers,
+	})
+	if err != nil {
+		log.Printf("[ERROR] consumer: republish for retry: %v", err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+func retryCount(d amqp.Delivery) int {
+	switch v := d.Headers["x-retry"].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func nextBackoff(cur, max time.Duration) time.Duration {
+	next := cur * 2
+	if next > max {
+		return max
+	}
+	return next
+}
@@ -0,0 +1,114 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// fakeAcknowledger is the mock Acknowledger amqp.Delivery's own doc comment
+// suggests tests provide, letting us drive handle()/retryOrDeadLetter
+// without a real AMQP channel.
+type fakeAcknowledger struct {
+	acked   bool
+	nacked  bool
+	requeue bool
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error { f.acked = true; return nil }
+func (f *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.nacked = true
+	f.requeue = requeue
+	return nil
+}
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+type fakeHandler struct {
+	err error
+}
+
+func (h fakeHandler) Handle(ctx context.Context, msg Message) error { return h.err }
+
+func TestConsumerPoolHandleAcksOnSuccess(t *testing.T) {
+	p := NewConsumerPool(Config{MaxRetries: 3}, fakeHandler{})
+	ack := &fakeAcknowledger{}
+	d := amqp.Delivery{Acknowledger: ack, Body: []byte(`{"campaign_id":"1","questionary_id":2}`)}
+
+	p.handle(context.Background(), d)
+
+	if !ack.acked {
+		t.Fatal("expected the delivery to be acked on handler success")
+	}
+	if ack.nacked {
+		t.Fatal("did not expect the delivery to be nacked on handler success")
+	}
+}
+
+func TestConsumerPoolHandleDeadLettersAfterMaxRetries(t *testing.T) {
+	p := NewConsumerPool(Config{MaxRetries: 3}, fakeHandler{err: errors.New("boom")})
+	ack := &fakeAcknowledger{}
+	d := amqp.Delivery{
+		Acknowledger: ack,
+		Headers:      amqp.Table{"x-retry": int32(3)},
+		Body:         []byte(`{"campaign_id":"1","questionary_id":2}`),
+	}
+
+	p.handle(context.Background(), d)
+
+	if !ack.nacked {
+		t.Fatal("expected the delivery to be nacked once MaxRetries is exhausted")
+	}
+	if ack.requeue {
+		t.Fatal("expected requeue=false so the broker dead-letters the message instead of redelivering it")
+	}
+}
+
+func TestConsumerPoolHandleUnmarshalFailureDeadLettersAfterMaxRetries(t *testing.T) {
+	p := NewConsumerPool(Config{MaxRetries: 3}, fakeHandler{})
+	ack := &fakeAcknowledger{}
+	d := amqp.Delivery{
+		Acknowledger: ack,
+		Headers:      amqp.Table{"x-retry": int32(3)},
+		Body:         []byte(`not json`),
+	}
+
+	p.handle(context.Background(), d)
+
+	if !ack.nacked {
+		t.Fatal("expected an unmarshal failure to count as a failed delivery and dead-letter once retries are exhausted")
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	cases := []struct {
+		name string
+		hdr  amqp.Table
+		want int
+	}{
+		{"missing", amqp.Table{}, 0},
+		{"int32", amqp.Table{"x-retry": int32(2)}, 2},
+		{"int64", amqp.Table{"x-retry": int64(4)}, 4},
+		{"int", amqp.Table{"x-retry": 7}, 7},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := amqp.Delivery{Headers: c.hdr}
+			if got := retryCount(d); got != c.want {
+				t.Fatalf("retryCount() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNextBackoffCapsAtMax(t *testing.T) {
+	max := 10 * time.Second
+	if got := nextBackoff(6*time.Second, max); got != max {
+		t.Fatalf("nextBackoff(6s, max=10s) = %v, want %v (capped)", got, max)
+	}
+	if got := nextBackoff(2*time.Second, max); got != 4*time.Second {
+		t.Fatalf("nextBackoff(2s, max=10s) = %v, want 4s", got)
+	}
+}
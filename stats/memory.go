@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, used in tests and local development
+// where a PostgreSQL instance isn't available.
+type MemoryStore struct {
+	mu    sync.Mutex
+	stats []Stat
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Record(stat Stat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = append(s.stats, stat)
+	return nil
+}
+
+func (s *MemoryStore) Query(filter Filter) ([]Bucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count, ok int64
+	for _, stat := range s.stats {
+		if !filter.match(stat) {
+			continue
+		}
+		count++
+		if stat.RecognizedOk {
+			ok++
+		}
+	}
+
+	return []Bucket{{Count: count, OkRate: okRate(count, ok)}}, nil
+}
+
+func (s *MemoryStore) GroupBy(dims []string, filter Filter) ([]GroupedBucket, error) {
+	for _, dim := range dims {
+		if _, ok := memoryDimFuncs[dim]; !ok {
+			return nil, fmt.Errorf("unknown group-by dimension %q", dim)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type agg struct {
+		dims    map[string]string
+		count   int64
+		okCount int64
+	}
+	groups := map[string]*agg{}
+
+	for _, stat := range s.stats {
+		if !filter.match(stat) {
+			continue
+		}
+
+		dimValues, err := dimValues(dims, stat)
+		if err != nil {
+			return nil, err
+		}
+		key := groupKey(dimValues)
+
+		a, found := groups[key]
+		if !found {
+			a = &agg{dims: dimValues}
+			groups[key] = a
+		}
+		a.count++
+		if stat.RecognizedOk {
+			a.okCount++
+		}
+	}
+
+	buckets := make([]GroupedBucket, 0, len(groups))
+	for _, a := range groups {
+		buckets = append(buckets, GroupedBucket{
+			Dims:   a.dims,
+			Count:  a.count,
+			OkRate: okRate(a.count, a.okCount),
+		})
+	}
+	return buckets, nil
+}
+
+// Snapshot returns a copy of every Stat recorded so far, e.g. to persist to
+// disk on shutdown.
+func (s *MemoryStore) Snapshot() []Stat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Stat, len(s.stats))
+	copy(out, s.stats)
+	return out
+}
+
+// memoryDimFuncs mirrors postgres.go's dimColumnNames: the same set of
+// group-by dimensions must be supported and rejected the same way by both
+// Store implementations.
+var memoryDimFuncs = map[string]func(Stat) string{
+	"campaign":      func(s Stat) string { return strconv.FormatInt(s.Campaign, 10) },
+	"template":      func(s Stat) string { return s.Template },
+	"field":         func(s Stat) string { return s.Field },
+	"sliced":        func(s Stat) string { return strconv.FormatBool(s.Sliced) },
+	"empty":         func(s Stat) string { return strconv.FormatBool(s.Empty) },
+	"recognized":    func(s Stat) string { return strconv.FormatBool(s.Recognized) },
+	"recognized_ok": func(s Stat) string { return strconv.FormatBool(s.RecognizedOk) },
+}
+
+func dimValues(dims []string, stat Stat) (map[string]string, error) {
+	values := make(map[string]string, len(dims))
+	for _, dim := range dims {
+		fn, ok := memoryDimFuncs[dim]
+		if !ok {
+			return nil, fmt.Errorf("unknown group-by dimension %q", dim)
+		}
+		values[dim] = fn(stat)
+	}
+	return values, nil
+}
+
+func groupKey(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(values[k])
+		b.WriteByte(';')
+	}
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package stats
+
+import "testing"
+
+func TestMemoryStoreGroupBySliced(t *testing.T) {
+	store := NewMemoryStore()
+	store.Record(Stat{Campaign: 1, Sliced: true})
+	store.Record(Stat{Campaign: 1, Sliced: false})
+
+	buckets, err := store.GroupBy([]string{"sliced"}, Filter{})
+	if err != nil {
+		t.Fatalf("GroupBy: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2 (one per Sliced value)", len(buckets))
+	}
+}
+
+func TestMemoryStoreGroupByUnknownDimension(t *testing.T) {
+	store := NewMemoryStore()
+	store.Record(Stat{Campaign: 1})
+
+	if _, err := store.GroupBy([]string{"bogus"}, Filter{}); err == nil {
+		t.Fatal("expected an error for an unrecognized group-by dimension")
+	}
+}
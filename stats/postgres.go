@@ -0,0 +1,181 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresStore is a Store backed by PostgreSQL. It expects a "stats" table
+// shaped like Stat: datetime timestamptz, campaign bigint, template text,
+// field text, sliced bool, empty bool, recognized bool, recognized_ok bool.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore connects to dsn and returns a Store backed by it.
+func NewPostgresStore(ctx context.Context, dsn string) (*PostgresStore, error) {
+	pool, err := pgxpool.Connect(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	return &PostgresStore{pool: pool}, nil
+}
+
+// Close releases the underlying connection pool.
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+func (s *PostgresStore) Record(stat Stat) error {
+	_, err := s.pool.Exec(context.Background(),
+		`INSERT INTO stats (datetime, campaign, template, field, sliced, empty, recognized, recognized_ok)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		stat.Datetime, stat.Campaign, stat.Template, stat.Field,
+		stat.Sliced, stat.Empty, stat.Recognized, stat.RecognizedOk,
+	)
+	if err != nil {
+		return fmt.Errorf("insert stat: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Query(filter Filter) ([]Bucket, error) {
+	where, args := filter.whereClause()
+
+	row := s.pool.QueryRow(context.Background(),
+		fmt.Sprintf(`SELECT count(*), coalesce(avg(recognized_ok::int), 0) FROM stats %s`, where),
+		args...,
+	)
+
+	var bucket Bucket
+	if err := row.Scan(&bucket.Count, &bucket.OkRate); err != nil {
+		return nil, fmt.Errorf("query stats: %w", err)
+	}
+	return []Bucket{bucket}, nil
+}
+
+func (s *PostgresStore) GroupBy(dims []string, filter Filter) ([]GroupedBucket, error) {
+	columns, err := dimColumns(dims)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := filter.whereClause()
+	query := fmt.Sprintf(
+		`SELECT %s, count(*), coalesce(avg(recognized_ok::int), 0) FROM stats %s GROUP BY %s`,
+		strings.Join(textCastColumns(columns), ", "), where, strings.Join(columns, ", "),
+	)
+
+	rows, err := s.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("group stats: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []GroupedBucket
+	for rows.Next() {
+		scanned := make([]string, len(dims))
+		dest := make([]interface{}, 0, len(dims)+2)
+		for i := range scanned {
+			dest = append(dest, &scanned[i])
+		}
+
+		var bucket GroupedBucket
+		dest = append(dest, &bucket.Count, &bucket.OkRate)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan group: %w", err)
+		}
+
+		bucket.Dims = make(map[string]string, len(dims))
+		for i, dim := range dims {
+			bucket.Dims[dim] = scanned[i]
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// dimColumnNames maps the public dimension/filter names used by Filter and
+// GroupBy to the "stats" table's column names.
+var dimColumnNames = map[string]string{
+	"campaign":      "campaign",
+	"template":      "template",
+	"field":         "field",
+	"sliced":        "sliced",
+	"empty":         "empty",
+	"recognized":    "recognized",
+	"recognized_ok": "recognized_ok",
+}
+
+func dimColumns(dims []string) ([]string, error) {
+	columns := make([]string, 0, len(dims))
+	for _, dim := range dims {
+		col, ok := dimColumnNames[dim]
+		if !ok {
+			return nil, fmt.Errorf("unknown group-by dimension %q", dim)
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// textCastColumns casts every column to text for the SELECT list. GroupBy
+// scans each dim into a *string regardless of its underlying column type
+// (bigint for campaign, bool for sliced/empty/recognized/recognized_ok), so
+// without the cast rows.Scan fails against anything but a text/varchar
+// column.
+func textCastColumns(columns []string) []string {
+	cast := make([]string, len(columns))
+	for i, col := range columns {
+		cast[i] = col + "::text"
+	}
+	return cast
+}
+
+// whereClause builds a parameterized SQL WHERE clause (or "" if f has no
+// constraints) and its positional arguments.
+func (f Filter) whereClause() (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	if f.From != nil {
+		args = append(args, *f.From)
+		clauses = append(clauses, fmt.Sprintf("datetime >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, *f.To)
+		clauses = append(clauses, fmt.Sprintf("datetime <= $%d", len(args)))
+	}
+	if len(f.CampaignIDs) > 0 {
+		args = append(args, f.CampaignIDs)
+		clauses = append(clauses, fmt.Sprintf("campaign = ANY($%d)", len(args)))
+	}
+	if len(f.Templates) > 0 {
+		args = append(args, f.Templates)
+		clauses = append(clauses, fmt.Sprintf("template = ANY($%d)", len(args)))
+	}
+	if f.Sliced != nil {
+		args = append(args, *f.Sliced)
+		clauses = append(clauses, fmt.Sprintf("sliced = $%d", len(args)))
+	}
+	if f.Empty != nil {
+		args = append(args, *f.Empty)
+		clauses = append(clauses, fmt.Sprintf("empty = $%d", len(args)))
+	}
+	if f.Recognized != nil {
+		args = append(args, *f.Recognized)
+		clauses = append(clauses, fmt.Sprintf("recognized = $%d", len(args)))
+	}
+	if f.RecognizedOk != nil {
+		args = append(args, *f.RecognizedOk)
+		clauses = append(clauses, fmt.Sprintf("recognized_ok = $%d", len(args)))
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
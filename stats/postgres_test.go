@@ -0,0 +1,25 @@
+package stats
+
+import "testing"
+
+// Guards against scanning a non-text column (campaign is bigint,
+// sliced/empty/recognized/recognized_ok are bool) into GroupBy's *string
+// destinations, which fails at rows.Scan against a real Postgres backend
+// unless the SELECT list casts those columns to text.
+func TestTextCastColumnsCastsEveryColumn(t *testing.T) {
+	columns, err := dimColumns([]string{"campaign", "sliced", "template"})
+	if err != nil {
+		t.Fatalf("dimColumns: %v", err)
+	}
+
+	want := []string{"campaign::text", "sliced::text", "template::text"}
+	got := textCastColumns(columns)
+	if len(got) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(got), len(want))
+	}
+	for i, col := range got {
+		if col != want[i] {
+			t.Fatalf("column %d = %q, want %q", i, col, want[i])
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package stats is the statistics aggregation subsystem: recording events
+// coming off the RabbitMQ queue and answering the counting/grouping queries
+// the HTTP API exposes.
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stat is one recorded statistics event.
+type Stat struct {
+	Datetime     time.Time `json:"datetime"`
+	Campaign     int64     `json:"campaign"`
+	Template     string    `json:"template"`
+	Field        string    `json:"field"`
+	Sliced       bool      `json:"sliced"`
+	Empty        bool      `json:"empty"`
+	Recognized   bool      `json:"recognized"`
+	RecognizedOk bool      `json:"recognizedok"`
+}
+
+// Filter narrows a Query or GroupBy to a subset of recorded Stats. A nil
+// field means "don't filter on this".
+type Filter struct {
+	From *time.Time
+	To   *time.Time
+
+	CampaignIDs []int64
+	Templates   []string
+
+	Sliced       *bool
+	Empty        *bool
+	Recognized   *bool
+	RecognizedOk *bool
+}
+
+func (f Filter) match(stat Stat) bool {
+	if f.From != nil && stat.Datetime.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && stat.Datetime.After(*f.To) {
+		return false
+	}
+	if len(f.CampaignIDs) > 0 && !containsInt64(f.CampaignIDs, stat.Campaign) {
+		return false
+	}
+	if len(f.Templates) > 0 && !containsString(f.Templates, stat.Template) {
+		return false
+	}
+	if f.Sliced != nil && stat.Sliced != *f.Sliced {
+		return false
+	}
+	if f.Empty != nil && stat.Empty != *f.Empty {
+		return false
+	}
+	if f.Recognized != nil && stat.Recognized != *f.Recognized {
+		return false
+	}
+	if f.RecognizedOk != nil && stat.RecognizedOk != *f.RecognizedOk {
+		return false
+	}
+	return true
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Bucket is one aggregated row returned by Query.
+type Bucket struct {
+	Count  int64   `json:"count"`
+	OkRate float64 `json:"ok_rate"`
+}
+
+// GroupedBucket is one aggregated row returned by GroupBy, keyed by the
+// requested dimension values.
+type GroupedBucket struct {
+	Dims   map[string]string `json:"dims"`
+	Count  int64             `json:"count"`
+	OkRate float64           `json:"ok_rate"`
+}
+
+// Store records Stats and answers counting/grouping queries over them.
+type Store interface {
+	// Record persists one Stat.
+	Record(stat Stat) error
+
+	// Query returns a single aggregated Bucket for everything matching
+	// filter.
+	Query(filter Filter) ([]Bucket, error)
+
+	// GroupBy returns one GroupedBucket per distinct combination of dims
+	// ("campaign", "template", "field", "sliced", "empty", "recognized" or
+	// "recognized_ok") among Stats matching filter. An unrecognized dim is
+	// an error rather than being silently dropped from the group key.
+	GroupBy(dims []string, filter Filter) ([]GroupedBucket, error)
+}
+
+// groupByDims is every dimension GroupBy accepts. It's the source client
+// callers should validate against, kept separate from each Store's own dim
+// table (dimColumnNames, memoryDimFuncs) so an HTTP handler can reject an
+// unknown dim as a client error before ever reaching the store.
+var groupByDims = map[string]bool{
+	"campaign":      true,
+	"template":      true,
+	"field":         true,
+	"sliced":        true,
+	"empty":         true,
+	"recognized":    true,
+	"recognized_ok": true,
+}
+
+// ValidateGroupByDims reports an error naming the first dim GroupBy would
+// not recognize, or nil if every dim in dims is valid.
+func ValidateGroupByDims(dims []string) error {
+	for _, dim := range dims {
+		if !groupByDims[dim] {
+			return fmt.Errorf("unknown group-by dimension %q", dim)
+		}
+	}
+	return nil
+}
+
+func okRate(count, ok int64) float64 {
+	if count == 0 {
+		return 0
+	}
+	return float64(ok) / float64(count)
+}
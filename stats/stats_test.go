@@ -0,0 +1,15 @@
+package stats
+
+import "testing"
+
+func TestValidateGroupByDims(t *testing.T) {
+	if err := ValidateGroupByDims([]string{"campaign", "sliced"}); err != nil {
+		t.Fatalf("ValidateGroupByDims: %v", err)
+	}
+	if err := ValidateGroupByDims(nil); err != nil {
+		t.Fatalf("ValidateGroupByDims(nil): %v", err)
+	}
+	if err := ValidateGroupByDims([]string{"bogus"}); err == nil {
+		t.Fatal("expected an error for an unrecognized group-by dimension")
+	}
+}